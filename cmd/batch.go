@@ -0,0 +1,101 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func init() {
+	rootCmd.PersistentFlags().IntP("jobs", "j", 1, "Number of input files to process concurrently. 0 means use all CPU cores.")
+}
+
+// jobCount resolves the --jobs flag to a concrete worker count.
+func jobCount() int {
+	jobs, _ := rootCmd.Flags().GetInt("jobs")
+	if jobs <= 0 {
+		return runtime.NumCPU()
+	}
+
+	return jobs
+}
+
+// batchContext returns a context that is cancelled when the process
+// receives SIGINT, so in-flight ffmpeg children started with
+// exec.CommandContext are killed cleanly instead of left running.
+func batchContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// runBatchFunc processes a single file. skipped true means the file was
+// intentionally not processed (e.g. not a video) and shouldn't count as a
+// failure.
+type runBatchFunc func(ctx context.Context, file string) (skipped bool, err error)
+
+// runBatch runs fn over files through a worker pool bounded by --jobs,
+// aggregating per-file errors so that one bad input doesn't kill the rest
+// of the batch, and prints a succeeded/failed/skipped summary once every
+// file is done.
+func runBatch(ctx context.Context, files []string, fn runBatchFunc) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(jobCount())
+
+	var succeeded, failed, skipped int
+	var mu sync.Mutex
+
+	for _, file := range files {
+		file := file
+
+		g.Go(func() error {
+			fileSkipped, err := fn(ctx, file)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case fileSkipped:
+				skipped++
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+				failed++
+			default:
+				succeeded++
+			}
+
+			// A single file's failure doesn't cancel the rest of the batch.
+			return nil
+		})
+	}
+
+	// Every worker returns nil, since fn's errors are counted above, so
+	// g.Wait always returns nil here; it only waits for the workers still
+	// running to finish.
+	_ = g.Wait()
+
+	fmt.Fprintf(os.Stderr, "%d succeeded, %d failed, %d skipped\n", succeeded, failed, skipped)
+}
@@ -0,0 +1,125 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "fmt"
+
+// crossFadeGraph builds the ffmpeg filter_complex graphs used to splice a
+// single input into a loop of count repeats, crossfading tDur seconds at
+// every join. The video and audio graphs visit the same clip boundaries
+// (0, tDur, length-tDur, length), so both are built from this one struct
+// to keep them from drifting apart.
+type crossFadeGraph struct {
+	count  uint16
+	tDur   uint16
+	length uint16
+}
+
+func newCrossFadeGraph(count uint16, tDur uint16, length uint16) crossFadeGraph {
+	return crossFadeGraph{count: count, tDur: tDur, length: length}
+}
+
+// splitLabels returns the count-1 "[p1N]"/"[p2N]" labels used to split the
+// crossfaded segment and the middle clip into enough copies to concat, plus
+// their paired interleaving for the final concat input list.
+func (g crossFadeGraph) splitLabels(p1 string, p2 string) (first string, second string, interleaved string) {
+	var i uint16 = 1
+	for ; i < g.count; i++ {
+		first = first + fmt.Sprintf("[%s%d]", p1, i)
+		second = second + fmt.Sprintf("[%s%d]", p2, i)
+		interleaved = interleaved + fmt.Sprintf("[%s%d][%s%d]", p1, i, p2, i)
+	}
+
+	return first, second, interleaved
+}
+
+// video returns the video filter_complex graph, naming its output label
+// outLabel.
+func (g crossFadeGraph) video(outLabel string) string {
+	count, tDur, length := g.count, g.tDur, g.length
+	cf, cl, cfcl := g.splitLabels("cf", "cl")
+
+	var a string
+
+	// length = 15, tDur = 5
+	a = a + fmt.Sprintf("[0:v]trim=start=0:end=%d,setpts=PTS-STARTPTS[clip1]; ", length-tDur)               // 0 - 10
+	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[clip2]; ", tDur, length-tDur)        // 5 - 10
+	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[clip3]; ", length-tDur, length)      // 10 - 15
+	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[fadeoutsrc]; ", length-tDur, length) // 10 - 15
+	a = a + fmt.Sprintf("[0:v]trim=start=0:end=%d,setpts=PTS-STARTPTS[fadeinsrc]; ", tDur)                  // 0 - 5
+
+	a = a + fmt.Sprintf("[fadeinsrc]format=pix_fmts=yuva420p, fade=t=in:st=0:d=%d:alpha=1[fadein]; ", tDur)
+	a = a + fmt.Sprintf("[fadeoutsrc]format=pix_fmts=yuva420p, fade=t=out:st=0:d=%d:alpha=1[fadeout]; ", tDur)
+
+	a = a + "[fadein]fifo[fadeinfifo]; "
+	a = a + "[fadeout]fifo[fadeoutfifo]; "
+	a = a + "[fadeoutfifo][fadeinfifo]overlay[crossfade]; "
+
+	a = a + fmt.Sprintf("[crossfade] split=%d %s ; ", count-1, cf)
+	a = a + fmt.Sprintf("[clip2] split=%d %s ; ", count-1, cl)
+
+	a = a + "[clip1]" + cfcl + "[clip3]"
+	// Final number of clips to concatenate is twice of count
+	a = a + fmt.Sprintf("concat=n=%d:v=1[%s]", count*2, outLabel)
+
+	return a
+}
+
+// audio mirrors video's clip structure over the input's audio stream,
+// crossfading with acrossfade instead of overlay, and names its output
+// label outLabel.
+func (g crossFadeGraph) audio(outLabel string) string {
+	count, tDur, length := g.count, g.tDur, g.length
+	acf, acl, acfcl := g.splitLabels("acf", "acl")
+
+	var a string
+
+	a = a + fmt.Sprintf("[0:a]atrim=start=%d:end=%d,asetpts=PTS-STARTPTS[aclip2]; ", tDur, length-tDur)
+	a = a + fmt.Sprintf("[0:a]atrim=start=%d:end=%d,asetpts=PTS-STARTPTS[aclip3]; ", length-tDur, length)
+	a = a + fmt.Sprintf("[0:a]atrim=start=0:end=%d,asetpts=PTS-STARTPTS[aclip1]; ", length-tDur)
+	a = a + fmt.Sprintf("[0:a]atrim=start=%d:end=%d,asetpts=PTS-STARTPTS[afadeoutsrc]; ", length-tDur, length)
+	a = a + fmt.Sprintf("[0:a]atrim=start=0:end=%d,asetpts=PTS-STARTPTS[afadeinsrc]; ", tDur)
+
+	// acrossfade already applies its own fade-out/fade-in curve to its two
+	// inputs, so the sources are fed in raw; pre-fading them with afade as
+	// well would square the curve and dip the midpoint volume.
+	a = a + fmt.Sprintf("[afadeoutsrc][afadeinsrc]acrossfade=d=%d[across]; ", tDur)
+
+	a = a + fmt.Sprintf("[across] asplit=%d %s ; ", count-1, acf)
+	a = a + fmt.Sprintf("[aclip2] asplit=%d %s ; ", count-1, acl)
+
+	a = a + "[aclip1]" + acfcl + "[aclip3]"
+	a = a + fmt.Sprintf("concat=n=%d:v=0:a=1[%s]", count*2, outLabel)
+
+	return a
+}
+
+// combined returns the video and audio graphs joined into a single
+// filter_complex, outputting [vout] and [aout].
+func (g crossFadeGraph) combined() string {
+	return g.video("vout") + "; " + g.audio("aout")
+}
+
+// filterComplexWithCrossFade keeps the historical video-only signature: it
+// returns the same graph as crossFadeGraph.video("output").
+func filterComplexWithCrossFade(count uint16, tDur uint16, length uint16) string {
+	return newCrossFadeGraph(count, tDur, length).video("output")
+}
@@ -0,0 +1,123 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/talha131/bmtool/internal/ffmpeg"
+)
+
+// hwAccels is the set of -hwaccel values we expose on the CLI. "none"
+// disables hardware decoding and is the default.
+var hwAccels = []string{"none", "auto", "vaapi", "vulkan", "videotoolbox", "cuda", "qsv", "amf"}
+
+func init() {
+	rootCmd.PersistentFlags().String("hwaccel", "none", fmt.Sprintf("Hardware acceleration to use for decoding. One of: %s", strings.Join(hwAccels, ", ")))
+	rootCmd.PersistentFlags().String("video-encoder", "", "Video encoder to pass to ffmpeg's -c:v (e.g. libx264, h264_nvenc, h264_amf, h264_vaapi, hevc_videotoolbox). Default lets ffmpeg choose.")
+	rootCmd.PersistentFlags().String("preset", "", "Encoder preset to pass to ffmpeg's -preset, when the chosen encoder supports it")
+	rootCmd.PersistentFlags().String("crf", "", "Constant rate factor to pass to ffmpeg's -crf, when the chosen encoder supports it")
+	rootCmd.PersistentFlags().String("bitrate", "", "Target bitrate to pass to ffmpeg's -b:v, mutually exclusive with --crf")
+}
+
+var (
+	encodersOnce      sync.Once
+	availableEncoders map[string]bool
+)
+
+// ffmpegEncoders returns the set of encoder names ffmpeg was built with, by
+// parsing `ffmpeg -hide_banner -encoders` once per run.
+func ffmpegEncoders() map[string]bool {
+	encodersOnce.Do(func() {
+		availableEncoders = make(map[string]bool)
+
+		out, err := exec.Command(app, "-hide_banner", "-encoders").Output()
+		if err != nil {
+			return
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(string(out)))
+		started := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !started {
+				if strings.HasPrefix(strings.TrimSpace(line), "---") {
+					started = true
+				}
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			availableEncoders[fields[1]] = true
+		}
+	})
+
+	return availableEncoders
+}
+
+// applyHWAccel wires the --hwaccel flag onto t.
+func applyHWAccel(t *ffmpeg.Transcoder) {
+	hwaccel, _ := rootCmd.Flags().GetString("hwaccel")
+	t.HWAccel(hwaccel)
+}
+
+// applyVideoCodec wires --video-encoder, --preset, --crf and --bitrate onto
+// t. If the requested encoder isn't available in this ffmpeg build, it
+// warns on stderr and leaves -c:v unset, same as when no encoder was
+// requested at all: then, unless --crf or --bitrate is given, -qscale:v 0
+// is passed.
+func applyVideoCodec(t *ffmpeg.Transcoder) {
+	encoder, _ := rootCmd.Flags().GetString("video-encoder")
+	preset, _ := rootCmd.Flags().GetString("preset")
+	crf, _ := rootCmd.Flags().GetString("crf")
+	bitrate, _ := rootCmd.Flags().GetString("bitrate")
+
+	if encoder != "" {
+		if ffmpegEncoders()[encoder] {
+			t.VideoCodec(encoder)
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: encoder %q not found in this ffmpeg build, falling back to default\n", encoder)
+			encoder = ""
+		}
+	}
+
+	if preset != "" {
+		t.Preset(preset)
+	}
+
+	switch {
+	case crf != "":
+		t.CRF(crf)
+	case bitrate != "":
+		t.Bitrate(bitrate)
+	case encoder == "":
+		t.Args("-qscale:v", "0")
+	}
+}
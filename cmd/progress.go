@@ -0,0 +1,174 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/talha131/bmtool/internal/ffmpeg"
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Suppress progress output")
+}
+
+// progressRows tracks which lines of a reserved on-screen block are
+// currently occupied by a progressReporter, so that --jobs > 1 renders one
+// bar per active file instead of every worker stomping on the same \r
+// line. Guarded by progressMu, which also serializes the terminal writes
+// themselves.
+var (
+	progressMu   sync.Mutex
+	progressRows []bool
+)
+
+// reserveProgressRow claims a line in the reserved block for a new
+// reporter, reusing a row freed by a finished one where possible, and
+// extending the block with a blank line when every row is in use. It
+// returns the row's index within progressRows.
+func reserveProgressRow() int {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	for i, occupied := range progressRows {
+		if !occupied {
+			progressRows[i] = true
+			return i
+		}
+	}
+
+	progressRows = append(progressRows, true)
+	fmt.Fprintln(os.Stderr)
+
+	return len(progressRows) - 1
+}
+
+// releaseProgressRow frees row for reuse by a later reporter.
+func releaseProgressRow(row int) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	progressRows[row] = false
+}
+
+// writeProgressRow rewrites row within the reserved block, leaving the
+// cursor where it found it.
+func writeProgressRow(row int, line string) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	up := len(progressRows) - row
+	fmt.Fprintf(os.Stderr, "\x1b[%dA\r\x1b[2K%s\x1b[%dB\r", up, line, up)
+}
+
+// progressReporter renders a single-line progress bar from the
+// ffmpeg.ProgressEvent channel emitted by a Transcoder run. When stdout is
+// not a TTY, or when --quiet is set, it falls back to printing one plain
+// line per update (or nothing at all). On a TTY it claims its own row out
+// of progressRows, so concurrent --jobs workers each get a stable line
+// instead of overwriting one shared line.
+type progressReporter struct {
+	label    string
+	expected float64 // expected duration of the output, in seconds
+	quiet    bool
+	isTTY    bool
+	row      int
+}
+
+// newProgressReporter builds a reporter for an ffmpeg run expected to
+// produce expectedSeconds of output. label is printed alongside the bar,
+// typically the output file name.
+func newProgressReporter(label string, expectedSeconds float64) *progressReporter {
+	quiet, _ := rootCmd.Flags().GetBool("quiet")
+	isTTY := isTerminal(os.Stdout)
+
+	p := &progressReporter{
+		label:    label,
+		expected: expectedSeconds,
+		quiet:    quiet,
+		isTTY:    isTTY,
+	}
+
+	if isTTY && !quiet {
+		p.row = reserveProgressRow()
+	}
+
+	return p
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return (fi.Mode() & os.ModeCharDevice) != 0
+}
+
+// watch drains events until the channel closes, rendering each one. It is
+// meant to run in its own goroutine alongside Transcoder.Run.
+func (p *progressReporter) watch(events <-chan ffmpeg.ProgressEvent) {
+	for ev := range events {
+		if !p.quiet {
+			p.render(ev)
+		}
+	}
+
+	if p.isTTY && !p.quiet {
+		releaseProgressRow(p.row)
+	}
+}
+
+func (p *progressReporter) render(ev ffmpeg.ProgressEvent) {
+	percent := 0.0
+	if p.expected > 0 {
+		percent = (float64(ev.OutTimeMs) / 1e6) / p.expected * 100
+		if percent > 100 {
+			percent = 100
+		}
+	}
+
+	line := fmt.Sprintf("%s: %5.1f%% frame=%-6s speed=%s", p.label, percent, ev.Frame, ev.Speed)
+
+	if !p.isTTY {
+		fmt.Fprintln(os.Stderr, line)
+		return
+	}
+
+	writeProgressRow(p.row, line)
+}
+
+// runTranscoder wires hardware acceleration, encoder selection and progress
+// reporting onto t and runs it to completion. label is printed alongside
+// the progress bar, typically the output file name.
+func runTranscoder(ctx context.Context, t *ffmpeg.Transcoder, label string, expectedSeconds float64) error {
+	applyHWAccel(t)
+	applyVideoCodec(t)
+
+	reporter := newProgressReporter(label, expectedSeconds)
+	progress := make(chan ffmpeg.ProgressEvent)
+	go reporter.watch(progress)
+
+	return t.Run(ctx, progress)
+}
@@ -21,17 +21,24 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/talha131/bmtool/internal/ffmpeg"
+)
+
+// Supported values for the --audio flag.
+const (
+	audioDrop      = "drop"
+	audioLoop      = "loop"
+	audioCrossfade = "crossfade"
 )
 
 // videoLoopCmd represents the videoLoop command
@@ -48,6 +55,7 @@ Output format is mp4.
 		length, errD := cmd.Flags().GetUint16("length")
 		crossFade, _ := cmd.Flags().GetBool("withCrossFade")
 		tDuration, _ := cmd.Flags().GetUint16("transitionDuration")
+		audioMode, _ := cmd.Flags().GetString("audio")
 
 		if errC != nil && errD != nil {
 			fmt.Fprint(os.Stderr, "Unable to find Count or Length. At least one is required")
@@ -59,30 +67,60 @@ Output format is mp4.
 			return
 		}
 
+		if crossFade && audioMode == audioLoop {
+			fmt.Fprint(os.Stderr, "--audio loop cannot be combined with --withCrossFade: the crossfaded video is shorter than count plain repeats, so a single un-looped pass of the original audio would run out partway through. Use --audio crossfade or --audio drop instead.")
+			return
+		}
+
 		oPath := createOutputDirectory(cmd)
 		shouldConcatCountTimes := length == 0 && errC == nil && count > 0
 		shouldConcatToAchieveLength := !shouldConcatCountTimes && errD == nil && length > 0
 
-		for _, e := range args {
-			if isFileVideo(e) {
-				if shouldConcatCountTimes {
-					outputFileName := getOutputFileName(oPath, e, fmt.Sprintf("%s-%d", "loop", count))
-					if !crossFade {
-						createVideoLoopWithoutTransition(count, e, outputFileName)
-					} else {
-						createVideoLoopWithTransition(count, tDuration, e, outputFileName)
-					}
-				} else if shouldConcatToAchieveLength {
-					count, err := getRequiredLoopCount(e, length)
-					if err == nil {
-						outputFileName := getOutputFileName(oPath, e, fmt.Sprintf("%s-%d", "length", length))
-						if !crossFade {
-							createVideoLoopWithoutTransition(count, e, outputFileName)
-						}
+		ctx, cancel := batchContext()
+		defer cancel()
+
+		runBatch(ctx, args, func(ctx context.Context, e string) (bool, error) {
+			if !isFileVideo(e) {
+				return true, nil
+			}
+
+			if shouldConcatCountTimes {
+				outputFileName := getOutputFileName(oPath, e, fmt.Sprintf("%s-%d", "loop", count))
+				if !crossFade {
+					expected := 0.0
+					if l, err := getLength(e); err == nil {
+						expected = l * float64(count)
 					}
+					return false, createVideoLoopWithoutTransition(ctx, count, e, outputFileName, expected, audioMode)
 				}
+				return false, createVideoLoopWithTransition(ctx, count, tDuration, e, outputFileName, audioMode)
 			}
-		}
+
+			if shouldConcatToAchieveLength {
+				fileLen, err := getLength(e)
+				if err != nil {
+					return false, err
+				}
+
+				if int(tDuration) >= int(fileLen) {
+					return false, fmt.Errorf("transition duration (%ds) must be less than %s's length (%ds)", tDuration, e, int(fileLen))
+				}
+
+				count, err := getRequiredLoopCount(int(fileLen), int(length), int(tDuration))
+				if err != nil {
+					return false, err
+				}
+
+				outputFileName := getOutputFileName(oPath, e, fmt.Sprintf("%s-%d", "length", length))
+				if !crossFade {
+					return false, createVideoLoopWithoutTransition(ctx, uint16(count), e, outputFileName, float64(length), audioMode)
+				}
+
+				return false, createVideoLoopWithTransition(ctx, uint16(count), tDuration, e, outputFileName, audioMode)
+			}
+
+			return true, nil
+		})
 	},
 }
 
@@ -94,50 +132,13 @@ func init() {
 	videoLoopCmd.Flags().BoolP("withCrossFade", "x", false, "Concatenate videos with cross fade transition")
 	videoLoopCmd.Flags().Uint16P("transitionDuration", "t", 2, "Transition duration. Default is 2 seconds.")
 	videoLoopCmd.Flags().StringP("outputDirectory", "o", "", "Output directory path. Default is current.")
+	videoLoopCmd.Flags().String("audio", audioLoop, "How to handle audio: drop, loop (preserve as-is, incompatible with --withCrossFade), or crossfade (crossfades audio with --withCrossFade; without it, audio is kept as with loop)")
 }
 
-func filterComplexWithCrossFade(count uint16, tDur uint16, length uint16) string {
-
-	cf := ""
-	cl := ""
-	cfcl := ""
-	var i uint16 = 1
-	for ; i < count; i++ {
-		cf = cf + fmt.Sprintf("[cf%d]", i)
-		cl = cl + fmt.Sprintf("[cl%d]", i)
-		cfcl = cfcl + fmt.Sprintf("[cf%d][cl%d]", i, i)
-	}
-
-	var a string
-
-	// length = 15, tDur = 5
-	a = a + fmt.Sprintf("[0:v]trim=start=0:end=%d,setpts=PTS-STARTPTS[clip1]; ", length-tDur)               // 0 - 10
-	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[clip2]; ", tDur, length-tDur)        // 5 - 10
-	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[clip3]; ", length-tDur, length)      // 10 - 15
-	a = a + fmt.Sprintf("[0:v]trim=start=%d:end=%d,setpts=PTS-STARTPTS[fadeoutsrc]; ", length-tDur, length) // 10 - 15
-	a = a + fmt.Sprintf("[0:v]trim=start=0:end=%d,setpts=PTS-STARTPTS[fadeinsrc]; ", tDur)                  // 0 - 5
-
-	a = a + fmt.Sprintf("[fadeinsrc]format=pix_fmts=yuva420p, fade=t=in:st=0:d=%d:alpha=1[fadein]; ", tDur)
-	a = a + fmt.Sprintf("[fadeoutsrc]format=pix_fmts=yuva420p, fade=t=out:st=0:d=%d:alpha=1[fadeout]; ", tDur)
-
-	a = a + "[fadein]fifo[fadeinfifo]; "
-	a = a + "[fadeout]fifo[fadeoutfifo]; "
-	a = a + "[fadeoutfifo][fadeinfifo]overlay[crossfade]; "
-
-	a = a + fmt.Sprintf("[crossfade] split=%d %s ; ", count-1, cf)
-	a = a + fmt.Sprintf("[clip2] split=%d %s ; ", count-1, cl)
-
-	a = a + "[clip1]" + cfcl + "[clip3]"
-	// Final number of clips to concatenate is twice of count
-	a = a + fmt.Sprintf("concat=n=%d:v=1[output]", count*2)
-
-	return a
-}
-
-func createVideoLoopWithTransition(count uint16, tDur uint16, file string, outputFileName string) {
+func createVideoLoopWithTransition(ctx context.Context, count uint16, tDur uint16, file string, outputFileName string, audioMode string) error {
 	l, err := getLength(file)
 	if err != nil {
-		return
+		return err
 	}
 
 	length := uint16(l)
@@ -146,25 +147,42 @@ func createVideoLoopWithTransition(count uint16, tDur uint16, file string, outpu
 		fmt.Fprint(os.Stderr, "Transition duration must be less than video length")
 	}
 
-	fc := filterComplexWithCrossFade(count, tDur, length)
+	graph := newCrossFadeGraph(count, tDur, length)
+
+	var fc string
+	var mapArgs []string
+	dropAudio := false
+
+	// audioLoop is rejected before reaching here (Run refuses to combine it
+	// with --withCrossFade), so this only ever sees crossfade or drop.
+	switch audioMode {
+	case audioCrossfade:
+		fc = graph.combined()
+		mapArgs = []string{"[vout]", "[aout]"}
+	default:
+		fc = graph.video("output")
+		mapArgs = []string{"[output]"}
+		dropAudio = true
+	}
 
 	if v, _ := rootCmd.Flags().GetBool("verbose"); v {
 		fmt.Printf("filter_complex is\n%s\n", fc)
 	}
 
-	cmd := exec.Command(app, "-hide_banner",
-		"-i", file,
-		"-an", "-filter_complex",
-		fc,
-		"-map", "[output]",
-		outputFileName)
+	// Crossfading eats tDur seconds at every join, so the final duration is
+	// shorter than a plain count x length loop.
+	expected := l*float64(count) - float64(tDur)*float64(count-1)
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	err = cmd.Run()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	t := ffmpeg.New().Input(file).FilterComplex(fc)
+	if dropAudio {
+		t.Args("-an")
+	}
+	for _, m := range mapArgs {
+		t.Map(m)
 	}
+	t.Output(outputFileName)
+
+	return runTranscoder(ctx, t, filepath.Base(outputFileName), expected)
 }
 
 func getRequiredLoopCount(length int, requiredLength int, tDuration int) (int, error) {
@@ -197,10 +215,10 @@ func getOutputFileName(oPath string, f string, suffix string) string {
 	return filepath.Join(oPath, fn)
 }
 
-func createVideoLoopWithoutTransition(count uint16, e string, output string) {
+func createVideoLoopWithoutTransition(ctx context.Context, count uint16, e string, output string, expectedSeconds float64, audioMode string) error {
 	tmpFile, err := ioutil.TempFile(filepath.Dir(e), getFileNameWithoutExtension(e))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	defer os.Remove(tmpFile.Name()) // clean up
@@ -216,29 +234,26 @@ func createVideoLoopWithoutTransition(count uint16, e string, output string) {
 	lineR := strings.Repeat(line, int(count))
 
 	if _, err := tmpFile.WriteString(lineR); err != nil {
-		log.Fatal(err)
+		return err
 	}
 	if err := tmpFile.Close(); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	runCommandVideoLoopWithoutTransition(tmpFile.Name(),
-		output)
+	return runCommandVideoLoopWithoutTransition(ctx, tmpFile.Name(),
+		output, expectedSeconds, audioMode)
 }
 
-func runCommandVideoLoopWithoutTransition(file string, output string) {
-
-	cmd := exec.Command(app, "-hide_banner",
-		"-f", "concat",
-		"-safe", "0",
-		"-i", file,
-		"-qscale:v", "0",
-		output)
+func runCommandVideoLoopWithoutTransition(ctx context.Context, file string, output string, expectedSeconds float64, audioMode string) error {
+	t := ffmpeg.New().
+		PreInputArgs("-f", "concat", "-safe", "0").
+		Input(file)
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	err := cmd.Run()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	if audioMode == audioDrop {
+		t.Args("-an")
 	}
+
+	t.Output(output)
+
+	return runTranscoder(ctx, t, filepath.Base(output), expectedSeconds)
 }
@@ -0,0 +1,44 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "testing"
+
+func TestGetRequiredLoopCount(t *testing.T) {
+	// file=30s, required=120s, tDur=5s
+	// count = ceil((120-5)/(30-5)) = 5
+	// final length = count*(fileLen-tDur) + tDur = 5*25 + 5 = 130s
+	const fileLen, requiredLen, tDur = 30, 120, 5
+
+	count, err := getRequiredLoopCount(fileLen, requiredLen, tDur)
+	if err != nil {
+		t.Fatalf("getRequiredLoopCount returned error: %v", err)
+	}
+
+	if count != 5 {
+		t.Fatalf("expected count 5, got %d", count)
+	}
+
+	finalLength := count*(fileLen-tDur) + tDur
+	if finalLength != 130 {
+		t.Fatalf("expected final length 130, got %d", finalLength)
+	}
+}
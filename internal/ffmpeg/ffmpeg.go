@@ -0,0 +1,252 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package ffmpeg is a small, fluent wrapper around the ffmpeg and ffprobe
+// binaries. Subcommands build up a transcode job declaratively instead of
+// assembling exec.Command argument slices by hand:
+//
+//	err := ffmpeg.New().
+//		Input(path).
+//		HWAccel("vaapi").
+//		FilterComplex(graph).
+//		Map("[vout]").
+//		Map("[aout]").
+//		VideoCodec("libx264").
+//		Output(out).
+//		Run(ctx, nil)
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ProgressEvent is one update parsed from ffmpeg's `-progress pipe:1`
+// output, emitted as a Transcoder runs.
+type ProgressEvent struct {
+	Frame     string
+	Speed     string
+	OutTimeMs int64
+	Progress  string // "continue" or "end"
+}
+
+// Transcoder builds up a single ffmpeg invocation. Create one with New.
+type Transcoder struct {
+	binary        string
+	hwaccel       string
+	preInputArgs  []string
+	inputs        []string
+	extraArgs     []string
+	filterComplex string
+	maps          []string
+	videoCodec    string
+	preset        string
+	crf           string
+	bitrate       string
+	output        string
+}
+
+// New returns a Transcoder that invokes the "ffmpeg" binary found on PATH.
+func New() *Transcoder {
+	return &Transcoder{binary: "ffmpeg"}
+}
+
+// Binary overrides the ffmpeg executable to invoke, e.g. a full path.
+func (t *Transcoder) Binary(path string) *Transcoder {
+	t.binary = path
+	return t
+}
+
+// HWAccel sets the -hwaccel value. Empty or "none" leaves it unset.
+func (t *Transcoder) HWAccel(v string) *Transcoder {
+	t.hwaccel = v
+	return t
+}
+
+// PreInputArgs adds input options, e.g. "-f", "concat", "-safe", "0". They
+// are emitted once, immediately before the first -i flag, so they apply to
+// the first input whatever order Input and PreInputArgs are called in.
+func (t *Transcoder) PreInputArgs(args ...string) *Transcoder {
+	t.preInputArgs = append(t.preInputArgs, args...)
+	return t
+}
+
+// Input adds a -i input. Can be called more than once.
+func (t *Transcoder) Input(path string) *Transcoder {
+	t.inputs = append(t.inputs, path)
+	return t
+}
+
+// Args appends raw arguments after the inputs and before -filter_complex,
+// e.g. "-an". Use it for flags this API doesn't model yet.
+func (t *Transcoder) Args(args ...string) *Transcoder {
+	t.extraArgs = append(t.extraArgs, args...)
+	return t
+}
+
+// FilterComplex sets the -filter_complex graph.
+func (t *Transcoder) FilterComplex(graph string) *Transcoder {
+	t.filterComplex = graph
+	return t
+}
+
+// Map adds a -map argument, e.g. "[vout]" or "0:a?".
+func (t *Transcoder) Map(label string) *Transcoder {
+	t.maps = append(t.maps, label)
+	return t
+}
+
+// VideoCodec sets -c:v.
+func (t *Transcoder) VideoCodec(codec string) *Transcoder {
+	t.videoCodec = codec
+	return t
+}
+
+// Preset sets -preset.
+func (t *Transcoder) Preset(preset string) *Transcoder {
+	t.preset = preset
+	return t
+}
+
+// CRF sets -crf. Mutually exclusive with Bitrate; whichever is set last
+// wins.
+func (t *Transcoder) CRF(crf string) *Transcoder {
+	t.crf = crf
+	t.bitrate = ""
+	return t
+}
+
+// Bitrate sets -b:v. Mutually exclusive with CRF; whichever is set last
+// wins.
+func (t *Transcoder) Bitrate(bitrate string) *Transcoder {
+	t.bitrate = bitrate
+	t.crf = ""
+	return t
+}
+
+// Output sets the output file path.
+func (t *Transcoder) Output(path string) *Transcoder {
+	t.output = path
+	return t
+}
+
+func (t *Transcoder) args() []string {
+	args := []string{"-hide_banner"}
+
+	if t.hwaccel != "" && t.hwaccel != "none" {
+		args = append(args, "-hwaccel", t.hwaccel)
+	}
+
+	args = append(args, t.preInputArgs...)
+
+	for _, in := range t.inputs {
+		args = append(args, "-i", in)
+	}
+
+	args = append(args, t.extraArgs...)
+
+	if t.filterComplex != "" {
+		args = append(args, "-filter_complex", t.filterComplex)
+	}
+
+	for _, m := range t.maps {
+		args = append(args, "-map", m)
+	}
+
+	if t.videoCodec != "" {
+		args = append(args, "-c:v", t.videoCodec)
+	}
+
+	if t.preset != "" {
+		args = append(args, "-preset", t.preset)
+	}
+
+	if t.crf != "" {
+		args = append(args, "-crf", t.crf)
+	} else if t.bitrate != "" {
+		args = append(args, "-b:v", t.bitrate)
+	}
+
+	args = append(args, "-progress", "pipe:1", "-nostats", t.output)
+
+	return args
+}
+
+// Run executes the transcode, blocking until it completes or ctx is
+// cancelled. If progress is non-nil, it must be read from concurrently
+// (typically from a goroutine started alongside Run): Run sends a
+// ProgressEvent on it for every update ffmpeg reports, and closes it
+// before returning.
+func (t *Transcoder) Run(ctx context.Context, progress chan<- ProgressEvent) error {
+	cmd := exec.CommandContext(ctx, t.binary, t.args()...)
+	cmd.Stderr = os.Stderr
+
+	if progress == nil {
+		return cmd.Run()
+	}
+
+	defer close(progress)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanProgress(stdout, progress)
+
+	return cmd.Wait()
+}
+
+func scanProgress(r io.Reader, progress chan<- ProgressEvent) {
+	scanner := bufio.NewScanner(r)
+	var ev ProgressEvent
+
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, value := parts[0], strings.TrimSpace(parts[1])
+
+		switch key {
+		case "frame":
+			ev.Frame = value
+		case "speed":
+			ev.Speed = value
+		case "out_time_ms":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				ev.OutTimeMs = n
+			}
+		case "progress":
+			ev.Progress = value
+			progress <- ev
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright © 2018 Talha Mansoor <talha131@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// Stream is one entry of ffprobe's "streams" array.
+type Stream struct {
+	CodecType string `json:"codec_type"`
+	CodecName string `json:"codec_name"`
+}
+
+// MediaInfo is a parsed `ffprobe -show_streams -show_format` result.
+type MediaInfo struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+	Streams []Stream `json:"streams"`
+}
+
+// Duration returns the container duration in seconds.
+func (m *MediaInfo) Duration() (float64, error) {
+	return strconv.ParseFloat(m.Format.Duration, 64)
+}
+
+// HasVideo reports whether any stream is a video stream.
+func (m *MediaInfo) HasVideo() bool {
+	for _, s := range m.Streams {
+		if s.CodecType == "video" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Probe runs ffprobe against path and returns its parsed media info.
+func Probe(path string) (*MediaInfo, error) {
+	out, err := exec.Command("ffprobe", "-v", "quiet", "-print_format", "json", "-show_streams", "-show_format", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+
+	var info MediaInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output for %s: %w", path, err)
+	}
+
+	return &info, nil
+}